@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source types a podcast entry can use to discover episodes.
+const (
+	sourceAdhoc = "adhoc"
+	sourceRSS   = "rss"
+)
+
+// PodcastConfig describes a single subscribed show.
+type PodcastConfig struct {
+	Shortname string
+	Name      string
+	Type      string // sourceAdhoc or sourceRSS
+	FeedURL   string
+	OutputDir string
+
+	// ListingURL and FilenameRegex configure an IndexResolver for adhoc
+	// podcasts run with -resolver=index. FilenameRegex must contain a
+	// "date" capture group.
+	ListingURL    string
+	FilenameRegex string
+}
+
+// defaultConfigPath returns the default location of the subscriptions file,
+// ~/.pacific-notions/podcasts.toml.
+func defaultConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolving home directory: %w", err)
+	}
+	return filepath.Join(home, ".pacific-notions", "podcasts.toml"), nil
+}
+
+// defaultAdhocConfig reproduces the tool's original hardcoded behavior: a
+// single KEXP Pacific Notions entry resolved by probing for the mysterious
+// number, used when no subscriptions file is present.
+func defaultAdhocConfig() PodcastConfig {
+	return PodcastConfig{
+		Shortname: "pacific-notions",
+		Name:      "Pacific Notions (KEXP)",
+		Type:      sourceAdhoc,
+		OutputDir: *outputDir,
+	}
+}
+
+// loadPodcastConfigs reads the subscriptions file at path. If the file does
+// not exist, it returns an error satisfying os.IsNotExist so callers can fall
+// back to the adhoc-only default.
+func loadPodcastConfigs(path string) ([]PodcastConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var configs []PodcastConfig
+	var cur *PodcastConfig
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if line == "[[podcast]]" {
+			if cur != nil {
+				if err := validatePodcastConfig(*cur, len(configs)+1); err != nil {
+					return nil, fmt.Errorf("parsing podcast config %s: %w", path, err)
+				}
+				configs = append(configs, *cur)
+			}
+			cur = &PodcastConfig{Type: sourceAdhoc}
+			continue
+		}
+
+		if cur == nil {
+			continue
+		}
+
+		key, value, ok := parseTOMLKeyValue(line)
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "shortname":
+			cur.Shortname = value
+		case "name":
+			cur.Name = value
+		case "type":
+			cur.Type = value
+		case "feed_url":
+			cur.FeedURL = value
+		case "output_dir":
+			cur.OutputDir = value
+		case "listing_url":
+			cur.ListingURL = value
+		case "filename_regex":
+			cur.FilenameRegex = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading podcast config %s: %w", path, err)
+	}
+	if cur != nil {
+		if err := validatePodcastConfig(*cur, len(configs)+1); err != nil {
+			return nil, fmt.Errorf("parsing podcast config %s: %w", path, err)
+		}
+		configs = append(configs, *cur)
+	}
+
+	return configs, nil
+}
+
+// validatePodcastConfig checks that a parsed [[podcast]] block has the
+// fields required for its type, so a typo'd or missing key fails loudly
+// here instead of surfacing as an opaque error deep inside os.MkdirAll.
+// index is the block's 1-based position in the file, used to identify it
+// in error messages when shortname itself is missing.
+func validatePodcastConfig(cfg PodcastConfig, index int) error {
+	if cfg.Shortname == "" {
+		return fmt.Errorf("podcast config entry %d: missing required field shortname", index)
+	}
+	if cfg.OutputDir == "" {
+		return fmt.Errorf("podcast %s: missing required field output_dir", cfg.Shortname)
+	}
+	if cfg.Type == sourceRSS && cfg.FeedURL == "" {
+		return fmt.Errorf("podcast %s: type=rss requires feed_url", cfg.Shortname)
+	}
+	return nil
+}
+
+// parseTOMLKeyValue parses a single `key = "value"` line from the minimal
+// TOML subset used for podcasts.toml. It reports ok=false for lines it
+// doesn't recognize.
+func parseTOMLKeyValue(line string) (key, value string, ok bool) {
+	parts := strings.SplitN(line, "=", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	key = strings.TrimSpace(parts[0])
+	value = strings.TrimSpace(parts[1])
+	value = strings.Trim(value, `"`)
+
+	return key, value, true
+}
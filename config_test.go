@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPodcastConfigs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "podcasts.toml")
+	contents := `
+# a comment line, and a blank line below should both be ignored
+
+[[podcast]]
+shortname = "pacific-notions"
+name = "Pacific Notions (KEXP)"
+type = "adhoc"
+output_dir = "./pacific-notions"
+listing_url = "https://kexp-archive.streamguys1.com/content/kexp/"
+filename_regex = "(?P<date>\d{8}).*-pacific-notions\.mp3"
+
+[[podcast]]
+shortname = "some-show"
+name = "Some Show"
+type = "rss"
+feed_url = "https://example.com/feed.xml"
+output_dir = "./some-show"
+`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing podcasts.toml: %v", err)
+	}
+
+	configs, err := loadPodcastConfigs(path)
+	if err != nil {
+		t.Fatalf("loadPodcastConfigs: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("loadPodcastConfigs: got %d entries, want 2", len(configs))
+	}
+
+	got := configs[0]
+	want := PodcastConfig{
+		Shortname:     "pacific-notions",
+		Name:          "Pacific Notions (KEXP)",
+		Type:          sourceAdhoc,
+		OutputDir:     "./pacific-notions",
+		ListingURL:    "https://kexp-archive.streamguys1.com/content/kexp/",
+		FilenameRegex: `(?P<date>\d{8}).*-pacific-notions\.mp3`,
+	}
+	if got != want {
+		t.Errorf("loadPodcastConfigs()[0] = %+v, want %+v", got, want)
+	}
+
+	if configs[1].Type != sourceRSS || configs[1].FeedURL != "https://example.com/feed.xml" {
+		t.Errorf("loadPodcastConfigs()[1] = %+v, want rss entry with feed_url set", configs[1])
+	}
+}
+
+func TestLoadPodcastConfigsMissingRequiredFields(t *testing.T) {
+	cases := []struct {
+		name     string
+		contents string
+	}{
+		{
+			name: "missing shortname",
+			contents: `
+[[podcast]]
+type = "adhoc"
+output_dir = "./x"
+`,
+		},
+		{
+			name: "missing output_dir",
+			contents: `
+[[podcast]]
+shortname = "x"
+type = "adhoc"
+`,
+		},
+		{
+			name: "rss without feed_url",
+			contents: `
+[[podcast]]
+shortname = "x"
+type = "rss"
+output_dir = "./x"
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "podcasts.toml")
+			if err := os.WriteFile(path, []byte(tc.contents), 0o644); err != nil {
+				t.Fatalf("writing podcasts.toml: %v", err)
+			}
+
+			if _, err := loadPodcastConfigs(path); err == nil {
+				t.Errorf("loadPodcastConfigs: want error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoadPodcastConfigsMissingFile(t *testing.T) {
+	_, err := loadPodcastConfigs(filepath.Join(t.TempDir(), "missing.toml"))
+	if !os.IsNotExist(err) {
+		t.Errorf("loadPodcastConfigs: got err %v, want an os.IsNotExist error", err)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// Downloader fetches episodes with bounded parallelism, retries, resume
+// support, and progress reporting. The zero value is not usable; construct
+// one with NewDownloader.
+type Downloader struct {
+	// MaxRetries is the number of additional attempts after a transient
+	// failure before giving up.
+	MaxRetries int
+	// Client is the HTTP client used for all requests.
+	Client *http.Client
+	// NewProgress builds the io.Writer progress updates are written to for
+	// a single download. It may be nil to disable progress reporting.
+	NewProgress func(label string, total int64) io.Writer
+	// AttemptTimeout bounds how long a single attempt may run, so a server
+	// that accepts the connection and then stalls mid-transfer doesn't hang
+	// its worker (and retry/backoff) forever.
+	AttemptTimeout time.Duration
+
+	sem chan struct{}
+}
+
+// NewDownloader returns a Downloader that runs at most maxParallel downloads
+// at once, reporting progress with a simple terminal progress bar.
+func NewDownloader(maxParallel int) *Downloader {
+	if maxParallel <= 0 {
+		maxParallel = 1
+	}
+	return &Downloader{
+		MaxRetries:     5,
+		Client:         &http.Client{},
+		NewProgress:    newTerminalProgress,
+		AttemptTimeout: 10 * time.Minute,
+		sem:            make(chan struct{}, maxParallel),
+	}
+}
+
+// Download fetches url into outputDir, resuming a partial download if a
+// matching .part file is already present and skipping the transfer entirely
+// if the state store's ETag/Last-Modified still match the server. On
+// success it records the response's validators in store under key.
+func (d *Downloader) Download(ctx context.Context, key, url, outputDir string, store *stateStore) error {
+	d.sem <- struct{}{}
+	defer func() { <-d.sem }()
+
+	var lastErr error
+	for attempt := 0; attempt <= d.MaxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<uint(attempt-1)) * 500 * time.Millisecond
+			debugPrintf("Retrying %s in %s (attempt %d/%d): %v\n", url, backoff, attempt, d.MaxRetries, lastErr)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, d.AttemptTimeout)
+		done, err := d.attempt(attemptCtx, key, url, outputDir, store)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if !done {
+			lastErr = err
+			continue
+		}
+		return err
+	}
+
+	return fmt.Errorf("downloading %s: giving up after %d attempts: %w", url, d.MaxRetries+1, lastErr)
+}
+
+// attempt runs a single download attempt. The bool return reports whether
+// the error (if any) is terminal and should not be retried.
+func (d *Downloader) attempt(ctx context.Context, key, rawURL, outputDir string, store *stateStore) (terminal bool, err error) {
+	filename := filenameForURL(rawURL)
+	outputPath := path.Join(outputDir, filename)
+	partPath := outputPath + ".part"
+
+	var resumeFrom int64
+	if info, statErr := os.Stat(partPath); statErr == nil {
+		resumeFrom = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return true, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+
+	entry, hasEntry := store.get(key)
+	previouslyDownloaded := hasEntry && !entry.DownloadedAt.IsZero()
+
+	if resumeFrom > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeFrom))
+	} else if hasEntry && entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	} else if hasEntry && entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		debugPrintf("%s not modified, skipping\n", rawURL)
+		if previouslyDownloaded {
+			// The server's copy is unchanged, yet the file is missing from
+			// disk: the user removed it on purpose. Remember that so future
+			// runs skip it without another request.
+			if err := store.markDeleted(key); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The .part file is stale (e.g. the server doesn't recognize our
+		// resume offset); drop it and let the caller retry from scratch.
+		_ = os.Remove(partPath)
+		return false, fmt.Errorf("downloading %s: range not satisfiable", rawURL)
+
+	case resp.StatusCode >= 500:
+		return false, fmt.Errorf("downloading %s: server error %s", rawURL, resp.Status)
+
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent:
+		return true, fmt.Errorf("downloading %s: unexpected status %s", rawURL, resp.Status)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if resp.StatusCode == http.StatusPartialContent {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+		resumeFrom = 0
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return true, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+
+	var w io.Writer = out
+	if d.NewProgress != nil {
+		total := resp.ContentLength
+		if total > 0 && resumeFrom > 0 {
+			total += resumeFrom
+		}
+		w = io.MultiWriter(out, d.NewProgress(filename, total))
+	}
+
+	_, copyErr := io.Copy(w, resp.Body)
+	closeErr := out.Close()
+	if copyErr != nil {
+		return false, fmt.Errorf("downloading %s: %w", rawURL, copyErr)
+	}
+	if closeErr != nil {
+		return true, fmt.Errorf("downloading %s: %w", rawURL, closeErr)
+	}
+
+	if err := os.Rename(partPath, outputPath); err != nil {
+		return true, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+
+	if err := store.markDownloadedWithHeaders(key, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// filenameForURL derives the on-disk filename for rawURL, stripping any
+// query string so tracking/redirect enclosure URLs (pdst.fm, chartable,
+// podtrac, etc.) don't leak "?utm_source=..." into the saved filename or
+// collide with unrelated episodes that share a generic redirect path.
+func filenameForURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return path.Base(rawURL)
+	}
+	return path.Base(u.Path)
+}
+
+// newTerminalProgress renders a simple one-line terminal progress bar to
+// stderr for a download of the given label and total size (zero or negative
+// if unknown).
+func newTerminalProgress(label string, total int64) io.Writer {
+	return &terminalProgress{out: os.Stderr, label: label, total: total}
+}
+
+type terminalProgress struct {
+	out     io.Writer
+	label   string
+	total   int64
+	written int64
+}
+
+// progressMu serializes writes from concurrent downloads to the shared
+// terminal, so their carriage-return progress lines don't interleave and
+// garble each other under the default parallel downloads.
+var progressMu sync.Mutex
+
+func (p *terminalProgress) Write(b []byte) (int, error) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	n := len(b)
+	p.written += int64(n)
+
+	if p.total > 0 {
+		pct := float64(p.written) / float64(p.total) * 100
+		fmt.Fprintf(p.out, "\r%s: %5.1f%% (%d/%d bytes)", p.label, pct, p.written, p.total)
+	} else {
+		fmt.Fprintf(p.out, "\r%s: %d bytes", p.label, p.written)
+	}
+	if p.total > 0 && p.written >= p.total {
+		fmt.Fprintln(p.out)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testDownloader returns a Downloader configured for fast, quiet tests: no
+// retries' worth of backoff delay and no progress output.
+func testDownloader() *Downloader {
+	d := NewDownloader(1)
+	d.MaxRetries = 0
+	d.NewProgress = nil
+	return d
+}
+
+func TestDownloaderAttemptMarksDeletedOnNotModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != `"abc"` {
+			t.Errorf("request missing If-None-Match validator, got %q", r.Header.Get("If-None-Match"))
+		}
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	store, err := openStateStore(dir)
+	if err != nil {
+		t.Fatalf("openStateStore: %v", err)
+	}
+
+	key := server.URL + "/episode.mp3"
+	if err := store.markDownloadedWithHeaders(key, `"abc"`, ""); err != nil {
+		t.Fatalf("markDownloadedWithHeaders: %v", err)
+	}
+
+	terminal, err := testDownloader().attempt(context.Background(), key, key, dir, store)
+	if err != nil {
+		t.Fatalf("attempt: %v", err)
+	}
+	if !terminal {
+		t.Errorf("attempt: want terminal=true for a 304 response")
+	}
+
+	entry, ok := store.get(key)
+	if !ok || !entry.Deleted {
+		t.Errorf("attempt: want %q marked deleted after a 304 for a previously-downloaded, now-missing file", key)
+	}
+}
+
+func TestDownloaderAttemptSkipsConditionalRequestWhenNeverDownloaded(t *testing.T) {
+	var gotIfNoneMatch bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			gotIfNoneMatch = true
+		}
+		w.Write([]byte("episode contents"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	store, err := openStateStore(dir)
+	if err != nil {
+		t.Fatalf("openStateStore: %v", err)
+	}
+
+	key := server.URL + "/episode.mp3"
+	terminal, err := testDownloader().attempt(context.Background(), key, key, dir, store)
+	if err != nil {
+		t.Fatalf("attempt: %v", err)
+	}
+	if !terminal {
+		t.Errorf("attempt: want terminal=true on success")
+	}
+	if gotIfNoneMatch {
+		t.Errorf("attempt: sent If-None-Match for an episode with no stored ETag")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, filenameForURL(key))); err != nil {
+		t.Errorf("attempt: downloaded file missing: %v", err)
+	}
+
+	entry, ok := store.get(key)
+	if !ok || entry.DownloadedAt.IsZero() {
+		t.Errorf("attempt: want %q recorded as downloaded", key)
+	}
+}
+
+func TestDownloaderAttemptResumesFromPartFile(t *testing.T) {
+	const full = "0123456789"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader := r.Header.Get("Range")
+		if rangeHeader != "bytes=5-" {
+			t.Errorf("request Range header = %q, want %q", rangeHeader, "bytes=5-")
+		}
+		w.Header().Set("Content-Range", "bytes 5-9/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(full[5:]))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	store, err := openStateStore(dir)
+	if err != nil {
+		t.Fatalf("openStateStore: %v", err)
+	}
+
+	key := server.URL + "/episode.mp3"
+	partPath := filepath.Join(dir, filenameForURL(key)+".part")
+	if err := os.WriteFile(partPath, []byte(full[:5]), 0o644); err != nil {
+		t.Fatalf("seeding .part file: %v", err)
+	}
+
+	if _, err := testDownloader().attempt(context.Background(), key, key, dir, store); err != nil {
+		t.Fatalf("attempt: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, filenameForURL(key)))
+	if err != nil {
+		t.Fatalf("reading completed download: %v", err)
+	}
+	if string(got) != full {
+		t.Errorf("completed download = %q, want %q", got, full)
+	}
+}
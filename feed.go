@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+)
+
+// rssFeed models the subset of an RSS podcast feed this tool cares about:
+// the enclosure URL of each item. Only feeds with an <rss> root element are
+// parsed; Atom feeds (a <feed> root) fail to decode.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title     string       `xml:"title"`
+	Enclosure rssEnclosure `xml:"enclosure"`
+}
+
+type rssEnclosure struct {
+	URL string `xml:"url,attr"`
+}
+
+// fetchFeedEnclosures downloads and parses the RSS feed at feedURL, returning
+// the enclosure URL of every item that has one.
+func fetchFeedEnclosures(feedURL string) ([]string, error) {
+	resp, err := http.Get(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching feed %s: %w", feedURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching feed %s: unexpected status %s", feedURL, resp.Status)
+	}
+
+	var feed rssFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("parsing feed %s: %w", feedURL, err)
+	}
+
+	urls := make([]string, 0, len(feed.Channel.Items))
+	for _, item := range feed.Channel.Items {
+		if item.Enclosure.URL != "" {
+			urls = append(urls, item.Enclosure.URL)
+		}
+	}
+
+	return urls, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchFeedEnclosures(t *testing.T) {
+	const body = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Some Show</title>
+    <item>
+      <title>Episode 1</title>
+      <enclosure url="https://cdn.example.com/ep1.mp3?utm=abc" type="audio/mpeg"/>
+    </item>
+    <item>
+      <title>Episode with no enclosure</title>
+    </item>
+    <item>
+      <title>Episode 2</title>
+      <enclosure url="https://cdn.example.com/ep2.mp3" type="audio/mpeg"/>
+    </item>
+  </channel>
+</rss>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	urls, err := fetchFeedEnclosures(server.URL)
+	if err != nil {
+		t.Fatalf("fetchFeedEnclosures: %v", err)
+	}
+
+	want := []string{
+		"https://cdn.example.com/ep1.mp3?utm=abc",
+		"https://cdn.example.com/ep2.mp3",
+	}
+	if len(urls) != len(want) {
+		t.Fatalf("fetchFeedEnclosures: got %v, want %v", urls, want)
+	}
+	for i, u := range urls {
+		if u != want[i] {
+			t.Errorf("fetchFeedEnclosures()[%d] = %q, want %q", i, u, want[i])
+		}
+	}
+}
+
+func TestFetchFeedEnclosuresUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	if _, err := fetchFeedEnclosures(server.URL); err == nil {
+		t.Error("fetchFeedEnclosures: want error for a non-200 response, got nil")
+	}
+}
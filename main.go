@@ -1,13 +1,17 @@
-// Downloads all the missing Pacific Notion (KEXP) podcasts for this month.
+// Downloads missing episodes for one or more subscribed podcasts, as
+// described by ~/.pacific-notions/podcasts.toml. Falls back to the
+// original hardcoded Pacific Notions (KEXP) adhoc lookup when no
+// subscriptions file is present.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"io"
 	"math"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"sync"
 	"time"
@@ -18,19 +22,163 @@ var (
 	usePreviousMonth = flag.Bool("previous-month", false, "Use the previous month instead of the current one")
 	previousMonths   = flag.Uint("p", 0, "Number of previous months to go back")
 	debug            = flag.Bool("debug", false, "Debug mode")
+	forgetDate       = flag.String("forget", "", "Clear stored state for the given date (YYYYMMDD) across all subscriptions and exit")
+	maxParallel      = flag.Int("parallel", 4, "Maximum number of simultaneous downloads")
+	resolverFlag     = flag.String("resolver", "probe", "Resolver strategy for adhoc podcasts: probe or index")
 )
 
 func main() {
 	flag.Parse()
 
-	// 1. Figure out which month and year to process
+	configs, err := loadSubscriptions()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *forgetDate != "" {
+		if err := forgetDateAcrossSubscriptions(configs, *forgetDate); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	downloader := NewDownloader(*maxParallel)
+
+	var wg sync.WaitGroup
+	for _, cfg := range configs {
+		wg.Add(1)
+		go func(cfg PodcastConfig) {
+			defer wg.Done()
+			if err := processPodcast(ctx, downloader, cfg); err != nil {
+				fmt.Println(err)
+			}
+		}(cfg)
+	}
+	wg.Wait()
+}
+
+// loadSubscriptions reads the user's podcasts.toml, falling back to the
+// original hardcoded KEXP entry when no subscriptions file exists.
+func loadSubscriptions() ([]PodcastConfig, error) {
+	configPath, err := defaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := loadPodcastConfigs(configPath)
+	if os.IsNotExist(err) {
+		return []PodcastConfig{defaultAdhocConfig()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return configs, nil
+}
+
+// forgetDateAcrossSubscriptions clears any stored state for date in every
+// subscription's state store, so it will be re-probed from scratch.
+func forgetDateAcrossSubscriptions(configs []PodcastConfig, date string) error {
+	for _, cfg := range configs {
+		store, err := openStateStore(cfg.OutputDir)
+		if err != nil {
+			return err
+		}
+		if err := store.forgetDate(date); err != nil {
+			return fmt.Errorf("forgetting %s for %s: %w", date, cfg.Shortname, err)
+		}
+	}
+	fmt.Printf("Forgot state for %s\n", date)
+	return nil
+}
+
+// downloadItem pairs an episode's state-store key with the URL to download.
+// For adhoc lookups the key is the episode's date; for RSS feeds it's the
+// enclosure URL itself.
+type downloadItem struct {
+	Key string
+	URL string
+}
+
+// processPodcast downloads whatever new episodes are available for a single
+// subscription, dispatching on its source type.
+func processPodcast(ctx context.Context, downloader *Downloader, cfg PodcastConfig) error {
+	if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
+		return fmt.Errorf("preparing output directory for %s: %w", cfg.Shortname, err)
+	}
+
+	store, err := openStateStore(cfg.OutputDir)
+	if err != nil {
+		return fmt.Errorf("opening state for %s: %w", cfg.Shortname, err)
+	}
+
+	var items []downloadItem
+	switch cfg.Type {
+	case sourceRSS:
+		urls, err := fetchFeedEnclosures(cfg.FeedURL)
+		if err != nil {
+			return err
+		}
+		for _, u := range urls {
+			items = append(items, downloadItem{Key: u, URL: u})
+		}
+	case sourceAdhoc:
+		resolver, err := buildResolver(cfg)
+		if err != nil {
+			return err
+		}
+		items = findAdhocLinks(store, resolver)
+	default:
+		return fmt.Errorf("podcast %s: unknown source type %q", cfg.Shortname, cfg.Type)
+	}
+
+	var wg sync.WaitGroup
+	for _, item := range items {
+		if !store.needsDownload(item.Key, cfg.OutputDir, item.URL) {
+			continue
+		}
+		wg.Add(1)
+		go func(item downloadItem) {
+			defer wg.Done()
+			if err := downloader.Download(ctx, item.Key, item.URL, cfg.OutputDir, store); err != nil {
+				fmt.Println(err)
+			}
+		}(item)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// buildResolver constructs the Resolver selected by -resolver for cfg.
+func buildResolver(cfg PodcastConfig) (Resolver, error) {
+	switch *resolverFlag {
+	case "probe":
+		return ProbeResolver{StartNumber: 12}, nil
+	case "index":
+		if cfg.ListingURL == "" || cfg.FilenameRegex == "" {
+			return nil, fmt.Errorf("podcast %s: -resolver=index requires listing_url and filename_regex in config", cfg.Shortname)
+		}
+		return NewIndexResolver(cfg.ListingURL, cfg.FilenameRegex)
+	default:
+		return nil, fmt.Errorf("unknown resolver %q", *resolverFlag)
+	}
+}
+
+// findAdhocLinks figures out the Sundays of the selected month (the current
+// one, or an earlier one with -previous-month or -p) and resolves each one's
+// download URL with resolver, skipping resolution entirely for dates
+// already resolved in store.
+func findAdhocLinks(store *stateStore, resolver Resolver) []downloadItem {
 	month, year := currentMonthAndYear()
 	month, year = adjustForPast(month, year)
 	debugPrintf("Month: %d, year: %d\n", month, year)
 
-	// 2. Get the list of podcasts for this month
-	//    a. Get sundays
-	//    b. Try to pick the correct URL for each sunday
 	sundays := findSundays(month, year)
 	if !*usePreviousMonth && *previousMonths == 0 {
 		sundays = filterSundaysUntilToday(sundays)
@@ -39,45 +187,38 @@ func main() {
 	debugPrintf("Sundays: %v\n", formattedSundays)
 
 	var wg sync.WaitGroup
-	validURLs := make(chan string)
+	itemsCh := make(chan downloadItem)
 	for _, v := range formattedSundays {
 		wg.Add(1)
 		go func(sunday string) {
 			defer wg.Done()
 
-			// find valid URL, does some magic, error-prone, requires constant updating
-			link := tryFindURLForDateMysteriosNumber(sunday, 12)
-			if len(link) > 0 {
-				debugPrintf("Valid URL: %v\n", link)
+			if entry, ok := store.get(sunday); ok && entry.URL != "" {
+				debugPrintf("Using resolved URL for %s: %v\n", sunday, entry.URL)
+				itemsCh <- downloadItem{Key: sunday, URL: entry.URL}
+				return
 			}
 
-			// filter missing downloads based on files in outputDir
-			if link != "" && isDownloadMissing(*outputDir, link) {
-				validURLs <- link
-				debugPrintf("Missing URL: %v\n", link)
+			link, mysteriousNumber := resolver.ResolveDate(sunday)
+			if len(link) > 0 {
+				debugPrintf("Valid URL: %v\n", link)
+				if err := store.markResolved(sunday, link, mysteriousNumber); err != nil {
+					fmt.Println(err)
+				}
+				itemsCh <- downloadItem{Key: sunday, URL: link}
 			}
 		}(v)
 	}
-	wg.Wait()
-	close(validURLs)
-
-	if len(validURLs) == 0 {
-		fmt.Println("No missing podcasts")
-		return
-	}
-
-	// 3. Download the podcasts that are missing
-	for link := range validURLs {
-		wg.Add(1)
-		go func(u string) {
-			defer wg.Done()
-			err := downloadFile(u, *outputDir)
-			if err != nil {
-				fmt.Println(err)
-			}
-		}(link)
+	go func() {
+		wg.Wait()
+		close(itemsCh)
+	}()
+
+	var items []downloadItem
+	for item := range itemsCh {
+		items = append(items, item)
 	}
-	wg.Wait()
+	return items
 }
 
 func currentMonthAndYear() (time.Month, int) {
@@ -155,9 +296,9 @@ func makeURLForDate(date string, mysteriousNumber int) string {
 	return fmt.Sprintf("https://kexp-archive.streamguys1.com/content/kexp/%s0550%02d-33-1962-pacific-notions.mp3", date, mysteriousNumber)
 }
 
-func tryFindURLForDateMysteriosNumber(date string, mysteriousNumber int) string {
+func tryFindURLForDateMysteriosNumber(date string, mysteriousNumber int) (string, int) {
 	if mysteriousNumber < 0 {
-		return ""
+		return "", -1
 	}
 
 	urlCandidate := makeURLForDate(date, mysteriousNumber)
@@ -165,42 +306,17 @@ func tryFindURLForDateMysteriosNumber(date string, mysteriousNumber int) string
 	resp, err := http.Head(urlCandidate)
 
 	if err == nil && resp.StatusCode == 200 {
-		return urlCandidate
+		return urlCandidate, mysteriousNumber
 	}
 
 	return tryFindURLForDateMysteriosNumber(date, mysteriousNumber-1)
 }
 
-func downloadFile(url string, outputDir string) error {
-	errMsgFormat := "failed downloading %s: %s"
-
-	fmt.Printf("Downloading %s\n", url)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf(errMsgFormat, url, err)
-	}
-	defer resp.Body.Close()
-
-	outputPath := path.Join(outputDir, path.Base(url))
-
-	out, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf(errMsgFormat, url, err)
-	}
-	defer out.Close()
-
-	if _, err = io.Copy(out, resp.Body); err != nil {
-		return fmt.Errorf(errMsgFormat, url, err)
-	}
-	return nil
-}
-
 func filterMissingDownloads(outputDir string, urls []string) []string {
 	missing := []string{}
 
 	for _, url := range urls {
-		outputPath := path.Join(outputDir, path.Base(url))
+		outputPath := path.Join(outputDir, filenameForURL(url))
 		if _, err := os.Stat(outputPath); os.IsNotExist(err) {
 			missing = append(missing, url)
 		}
@@ -210,7 +326,7 @@ func filterMissingDownloads(outputDir string, urls []string) []string {
 }
 
 func isDownloadMissing(outputDir, link string) bool {
-	outputPath := path.Join(outputDir, path.Base(link))
+	outputPath := path.Join(outputDir, filenameForURL(link))
 	_, err := os.Stat(outputPath)
 	return os.IsNotExist(err) == true
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// Resolver finds the download URL for a given episode date (YYYYMMDD).
+// mysteriousNumber is only meaningful for ProbeResolver and is -1 for
+// resolvers that don't use one.
+type Resolver interface {
+	ResolveDate(date string) (link string, mysteriousNumber int)
+}
+
+// ProbeResolver is the tool's original strategy: recursively HEAD-probing
+// candidate URLs for a decreasing "mysterious number" until one responds.
+type ProbeResolver struct {
+	StartNumber int
+}
+
+func (r ProbeResolver) ResolveDate(date string) (string, int) {
+	return tryFindURLForDateMysteriosNumber(date, r.StartNumber)
+}
+
+// hrefPattern extracts href attribute values from an HTML directory listing.
+var hrefPattern = regexp.MustCompile(`href="([^"]+)"`)
+
+// IndexResolver fetches an archive's directory listing once and matches
+// filenames against Pattern, which must have a "date" capture group holding
+// the YYYYMMDD the filename belongs to. This replaces ProbeResolver's
+// recursive HEAD storm with a single request.
+type IndexResolver struct {
+	ListingURL string
+	Pattern    *regexp.Regexp
+
+	mu      sync.Mutex
+	loaded  bool
+	loadErr error
+	byDate  map[string]string
+}
+
+// NewIndexResolver compiles filenamePattern, which must contain a "date"
+// named capture group, and returns a resolver for the given listing URL.
+func NewIndexResolver(listingURL, filenamePattern string) (*IndexResolver, error) {
+	pattern, err := regexp.Compile(filenamePattern)
+	if err != nil {
+		return nil, fmt.Errorf("compiling filename pattern %q: %w", filenamePattern, err)
+	}
+	if pattern.SubexpIndex("date") == -1 {
+		return nil, fmt.Errorf("filename pattern %q must have a \"date\" capture group", filenamePattern)
+	}
+	return &IndexResolver{ListingURL: listingURL, Pattern: pattern}, nil
+}
+
+func (r *IndexResolver) ResolveDate(date string) (string, int) {
+	if err := r.load(); err != nil {
+		debugPrintf("loading index %s: %v\n", r.ListingURL, err)
+		return "", -1
+	}
+	return r.byDate[date], -1
+}
+
+// load fetches and parses the listing exactly once, caching the result for
+// subsequent ResolveDate calls.
+func (r *IndexResolver) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		return r.loadErr
+	}
+	r.loaded = true
+	r.byDate, r.loadErr = r.fetchIndex()
+	return r.loadErr
+}
+
+func (r *IndexResolver) fetchIndex() (map[string]string, error) {
+	resp, err := http.Get(r.ListingURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching index %s: %w", r.ListingURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching index %s: unexpected status %s", r.ListingURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading index %s: %w", r.ListingURL, err)
+	}
+
+	base, err := url.Parse(r.ListingURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing listing URL %s: %w", r.ListingURL, err)
+	}
+
+	dateField := r.Pattern.SubexpIndex("date")
+	byDate := make(map[string]string)
+	for _, name := range parseIndexFilenames(data) {
+		match := r.Pattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+
+		ref, err := url.Parse(name)
+		if err != nil {
+			continue
+		}
+		byDate[match[dateField]] = base.ResolveReference(ref).String()
+	}
+
+	return byDate, nil
+}
+
+// parseIndexFilenames extracts candidate filenames from a directory listing
+// response, supporting either a JSON array of filenames or an HTML page with
+// <a href="..."> entries.
+func parseIndexFilenames(data []byte) []string {
+	var filenames []string
+	if err := json.Unmarshal(data, &filenames); err == nil {
+		return filenames
+	}
+
+	for _, match := range hrefPattern.FindAllStringSubmatch(string(data), -1) {
+		filenames = append(filenames, match[1])
+	}
+	return filenames
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewIndexResolverRequiresDateCaptureGroup(t *testing.T) {
+	if _, err := NewIndexResolver("https://example.com/", `\d{8}-pacific-notions\.mp3`); err == nil {
+		t.Error("NewIndexResolver: want error for a pattern with no \"date\" capture group, got nil")
+	}
+
+	if _, err := NewIndexResolver("https://example.com/", `(?P<date>\d{8})-pacific-notions\.mp3`); err != nil {
+		t.Errorf("NewIndexResolver: unexpected error: %v", err)
+	}
+}
+
+func TestIndexResolverResolveDateJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`["20240107-pacific-notions.mp3", "20240114-pacific-notions.mp3", "readme.txt"]`))
+	}))
+	defer server.Close()
+
+	resolver, err := NewIndexResolver(server.URL+"/", `(?P<date>\d{8})-pacific-notions\.mp3`)
+	if err != nil {
+		t.Fatalf("NewIndexResolver: %v", err)
+	}
+
+	link, number := resolver.ResolveDate("20240107")
+	if link != server.URL+"/20240107-pacific-notions.mp3" {
+		t.Errorf("ResolveDate(20240107) link = %q, want resolved against listing URL", link)
+	}
+	if number != -1 {
+		t.Errorf("ResolveDate(20240107) mysteriousNumber = %d, want -1", number)
+	}
+
+	if link, _ := resolver.ResolveDate("20240121"); link != "" {
+		t.Errorf("ResolveDate(20240121) = %q, want empty for a date not in the index", link)
+	}
+}
+
+func TestIndexResolverResolveDateHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>
+			<a href="20240107-pacific-notions.mp3">20240107-pacific-notions.mp3</a>
+			<a href="../other-show/20240107-other-show.mp3">not a match</a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	resolver, err := NewIndexResolver(server.URL+"/content/", `(?P<date>\d{8})-pacific-notions\.mp3`)
+	if err != nil {
+		t.Fatalf("NewIndexResolver: %v", err)
+	}
+
+	link, _ := resolver.ResolveDate("20240107")
+	if link != server.URL+"/content/20240107-pacific-notions.mp3" {
+		t.Errorf("ResolveDate(20240107) = %q, want HTML href resolved against the listing URL", link)
+	}
+}
+
+func TestIndexResolverLoadsListingOnce(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`["20240107-pacific-notions.mp3"]`))
+	}))
+	defer server.Close()
+
+	resolver, err := NewIndexResolver(server.URL+"/", `(?P<date>\d{8})-pacific-notions\.mp3`)
+	if err != nil {
+		t.Fatalf("NewIndexResolver: %v", err)
+	}
+
+	resolver.ResolveDate("20240107")
+	resolver.ResolveDate("20240114")
+	if requests != 1 {
+		t.Errorf("listing fetched %d times, want exactly 1", requests)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stateFileName is the per-output-directory database that tracks what has
+// already been resolved and downloaded, so repeat runs don't re-probe or
+// re-download episodes the user removed on purpose.
+const stateFileName = ".pacific-notions.db"
+
+// episodeState records everything we know about one episode, keyed by a
+// caller-chosen identifier (the Sunday's date for adhoc lookups, the
+// enclosure URL for RSS feeds).
+type episodeState struct {
+	Key              string    `json:"key"`
+	URL              string    `json:"url,omitempty"`
+	MysteriousNumber int       `json:"mysterious_number,omitempty"`
+	ETag             string    `json:"etag,omitempty"`
+	LastModified     string    `json:"last_modified,omitempty"`
+	DownloadedAt     time.Time `json:"downloaded_at,omitempty"`
+	Deleted          bool      `json:"deleted,omitempty"`
+}
+
+// stateStore is a small JSON-backed database living alongside a podcast's
+// downloads, one file per output directory.
+type stateStore struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]episodeState
+}
+
+// openStateStore loads the state file under outputDir, creating an empty
+// store if it doesn't exist yet.
+func openStateStore(outputDir string) (*stateStore, error) {
+	store := &stateStore{
+		path:    filepath.Join(outputDir, stateFileName),
+		entries: make(map[string]episodeState),
+	}
+
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state file %s: %w", store.path, err)
+	}
+
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("parsing state file %s: %w", store.path, err)
+	}
+
+	return store, nil
+}
+
+// get returns the stored state for key, if any.
+func (s *stateStore) get(key string) (episodeState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	return entry, ok
+}
+
+// markResolved records the URL and mysterious number an adhoc probe found
+// for key, so future runs can skip probing entirely.
+func (s *stateStore) markResolved(key, url string, mysteriousNumber int) error {
+	s.mu.Lock()
+	entry := s.entries[key]
+	entry.Key = key
+	entry.URL = url
+	entry.MysteriousNumber = mysteriousNumber
+	s.entries[key] = entry
+	s.mu.Unlock()
+	return s.save()
+}
+
+// markDownloaded records that key was just downloaded successfully.
+func (s *stateStore) markDownloaded(key string) error {
+	return s.markDownloadedWithHeaders(key, "", "")
+}
+
+// markDownloadedWithHeaders records that key was just downloaded
+// successfully, along with the ETag/Last-Modified validators the server
+// returned, so future runs can make conditional requests.
+func (s *stateStore) markDownloadedWithHeaders(key, etag, lastModified string) error {
+	s.mu.Lock()
+	entry := s.entries[key]
+	entry.Key = key
+	entry.DownloadedAt = time.Now()
+	entry.Deleted = false
+	if etag != "" {
+		entry.ETag = etag
+	}
+	if lastModified != "" {
+		entry.LastModified = lastModified
+	}
+	s.entries[key] = entry
+	s.mu.Unlock()
+	return s.save()
+}
+
+// needsDownload reports whether link should be downloaded for key. A file
+// already confirmed deleted on purpose (see markDeleted) is not
+// re-downloaded; otherwise a missing file is downloaded, giving a
+// previously-downloaded-but-now-missing episode a chance at conditional
+// revalidation in Downloader.attempt before it's marked deleted.
+func (s *stateStore) needsDownload(key, outputDir, link string) bool {
+	if !isDownloadMissing(outputDir, link) {
+		return false
+	}
+
+	entry, ok := s.get(key)
+	if ok && entry.Deleted {
+		return false
+	}
+
+	return true
+}
+
+// markDeleted records that key's file was found missing and a conditional
+// request confirmed the server's copy is unchanged, so the absence is
+// treated as an intentional user deletion rather than re-downloaded.
+func (s *stateStore) markDeleted(key string) error {
+	s.mu.Lock()
+	entry := s.entries[key]
+	entry.Key = key
+	entry.Deleted = true
+	s.entries[key] = entry
+	s.mu.Unlock()
+	return s.save()
+}
+
+// forgetDate removes all stored state for date, so the corresponding
+// episode(s) will be probed and downloaded again from scratch on the next
+// run. Adhoc entries are keyed directly by date; RSS entries are keyed by
+// their enclosure URL, so they're matched by checking whether the key's
+// resolved filename starts with date instead.
+func (s *stateStore) forgetDate(date string) error {
+	s.mu.Lock()
+	removed := false
+	for key := range s.entries {
+		if key == date || strings.HasPrefix(filenameForURL(key), date) {
+			delete(s.entries, key)
+			removed = true
+		}
+	}
+	s.mu.Unlock()
+	if !removed {
+		return nil
+	}
+	return s.save()
+}
+
+// save writes the store to disk as JSON. Callers must not hold s.mu.
+func (s *stateStore) save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encoding state file %s: %w", s.path, err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		return fmt.Errorf("writing state file %s: %w", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestForgetDate(t *testing.T) {
+	cases := []struct {
+		name     string
+		key      string
+		date     string
+		wantGone bool
+	}{
+		{
+			name:     "adhoc entry keyed directly by date",
+			key:      "20240107",
+			date:     "20240107",
+			wantGone: true,
+		},
+		{
+			name:     "rss entry keyed by enclosure url with tracking query string",
+			key:      "https://cdn.example.com/20240107-pacific-notions.mp3?utm=abc",
+			date:     "20240107",
+			wantGone: true,
+		},
+		{
+			name:     "unrelated date is left alone",
+			key:      "https://cdn.example.com/20240114-pacific-notions.mp3",
+			date:     "20240107",
+			wantGone: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store, err := openStateStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("openStateStore: %v", err)
+			}
+			if err := store.markDownloadedWithHeaders(tc.key, "", ""); err != nil {
+				t.Fatalf("markDownloadedWithHeaders: %v", err)
+			}
+
+			if err := store.forgetDate(tc.date); err != nil {
+				t.Fatalf("forgetDate: %v", err)
+			}
+
+			_, ok := store.get(tc.key)
+			if tc.wantGone && ok {
+				t.Errorf("forgetDate(%q): entry %q still present", tc.date, tc.key)
+			}
+			if !tc.wantGone && !ok {
+				t.Errorf("forgetDate(%q): entry %q was removed, want kept", tc.date, tc.key)
+			}
+		})
+	}
+}
+
+func TestNeedsDownload(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openStateStore(dir)
+	if err != nil {
+		t.Fatalf("openStateStore: %v", err)
+	}
+
+	link := "https://cdn.example.com/episode.mp3"
+	key := link
+
+	if !store.needsDownload(key, dir, link) {
+		t.Fatalf("needsDownload: want true for a never-seen episode")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, filenameForURL(link)), []byte("data"), 0o644); err != nil {
+		t.Fatalf("writing fake episode file: %v", err)
+	}
+	if store.needsDownload(key, dir, link) {
+		t.Errorf("needsDownload: want false once the file exists on disk")
+	}
+
+	if err := os.Remove(filepath.Join(dir, filenameForURL(link))); err != nil {
+		t.Fatalf("removing fake episode file: %v", err)
+	}
+	if !store.needsDownload(key, dir, link) {
+		t.Errorf("needsDownload: want true again once the file goes missing, pending revalidation")
+	}
+
+	if err := store.markDeleted(key); err != nil {
+		t.Fatalf("markDeleted: %v", err)
+	}
+	if store.needsDownload(key, dir, link) {
+		t.Errorf("needsDownload: want false once the episode is marked user-deleted")
+	}
+}